@@ -0,0 +1,251 @@
+// Command accrual-mock serves a stand-in for the accrual system's
+// GET /api/orders/{number} endpoint so the loyalty service can be run and
+// tested locally without the real accrual binary.
+//
+// Orders are registered the way the real service expects, with
+// POST /api/orders and a {"order": "<number>"} body; GET for a number that was
+// never registered answers 204. With -auto-register (the default) the mock
+// also registers any number the first time it is requested, so the loyalty
+// service can be pointed at it without a separate registration step.
+//
+// Registered numbers failing the Luhn check are reported as INVALID; the rest
+// move from REGISTERED to PROCESSING to PROCESSED, one status per -step
+// interval.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type config struct {
+	RunAddress   string
+	Latency      time.Duration
+	Step         time.Duration
+	RateLimit    int
+	Accrual      float64
+	AutoRegister bool
+}
+
+func newConfig() config {
+	var c config
+	flag.StringVar(&c.RunAddress, "a", ":8081", "address and port to run the mock on")
+	flag.DurationVar(&c.Latency, "latency", 0, "delay added to every response")
+	flag.DurationVar(&c.Step, "step", 5*time.Second, "time an order spends in each intermediate status")
+	flag.IntVar(&c.RateLimit, "rpm", 0, "requests per minute before answering 429, 0 disables the limit")
+	flag.Float64Var(&c.Accrual, "accrual", 500, "points awarded to PROCESSED orders")
+	flag.BoolVar(&c.AutoRegister, "auto-register", true, "register unknown order numbers on first request instead of answering 204")
+	flag.Parse()
+
+	if v := os.Getenv("RUN_ADDRESS"); v != "" {
+		c.RunAddress = v
+	}
+	envValue("MOCK_LATENCY", &c.Latency, time.ParseDuration)
+	envValue("MOCK_STATUS_STEP", &c.Step, time.ParseDuration)
+	envValue("MOCK_RATE_LIMIT", &c.RateLimit, strconv.Atoi)
+	envValue("MOCK_ACCRUAL", &c.Accrual, func(v string) (float64, error) {
+		return strconv.ParseFloat(v, 64)
+	})
+	envValue("MOCK_AUTO_REGISTER", &c.AutoRegister, strconv.ParseBool)
+	return c
+}
+
+// envValue overrides *dst with the parsed value of the environment variable
+// name. Unset or empty variables keep the flag value; a value that fails to
+// parse stops the mock rather than being silently ignored.
+func envValue[T any](name string, dst *T, parse func(string) (T, error)) {
+	v := os.Getenv(name)
+	if v == "" {
+		return
+	}
+	parsed, err := parse(v)
+	if err != nil {
+		log.Fatalf("invalid %s=%q: %v", name, v, err)
+	}
+	*dst = parsed
+}
+
+type registerRequest struct {
+	Order string `json:"order"`
+}
+
+type orderResponse struct {
+	Order   string   `json:"order"`
+	Status  string   `json:"status"`
+	Accrual *float64 `json:"accrual,omitempty"`
+}
+
+type server struct {
+	cfg config
+	now func() time.Time
+
+	mu          sync.Mutex
+	registered  map[string]time.Time
+	windowStart time.Time
+	windowCount int
+}
+
+func newServer(cfg config) *server {
+	return &server{cfg: cfg, now: time.Now, registered: make(map[string]time.Time)}
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/orders", s.handleRegister)
+	mux.HandleFunc("/api/orders/", s.handleOrder)
+	return mux
+}
+
+// allow counts the request against the current one-minute window and reports
+// whether it fits in the configured rate limit.
+func (s *server) allow(now time.Time) bool {
+	if s.cfg.RateLimit <= 0 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.windowStart) >= time.Minute {
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	s.windowCount++
+	return s.windowCount <= s.cfg.RateLimit
+}
+
+// register records number as registered at now and reports false if it was
+// already known.
+func (s *server) register(number string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.registered[number]; ok {
+		return false
+	}
+	s.registered[number] = now
+	return true
+}
+
+// registeredAt returns when number was registered. Unknown numbers are
+// registered at now when auto-registration is enabled, otherwise ok is false.
+func (s *server) registeredAt(number string, now time.Time) (at time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, ok = s.registered[number]
+	if !ok && s.cfg.AutoRegister {
+		at, ok = now, true
+		s.registered[number] = at
+	}
+	return at, ok
+}
+
+func (s *server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Order == "" {
+		http.Error(w, "invalid request format", http.StatusBadRequest)
+		return
+	}
+	if !s.register(req.Order, s.now()) {
+		http.Error(w, "order already registered", http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *server) handleOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if s.cfg.Latency > 0 {
+		select {
+		case <-time.After(s.cfg.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	now := s.now()
+	if !s.allow(now) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintf(w, "No more than %d requests per minute allowed", s.cfg.RateLimit)
+		return
+	}
+
+	number := strings.TrimPrefix(r.URL.Path, "/api/orders/")
+	registeredAt, ok := s.registeredAt(number, now)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	resp := orderResponse{Order: number}
+	if !isValidLuhn(number) {
+		resp.Status = "INVALID"
+	} else {
+		switch elapsed := now.Sub(registeredAt); {
+		case elapsed < s.cfg.Step:
+			resp.Status = "REGISTERED"
+		case elapsed < 2*s.cfg.Step:
+			resp.Status = "PROCESSING"
+		default:
+			resp.Status = "PROCESSED"
+			accrual := s.cfg.Accrual
+			resp.Accrual = &accrual
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("encode response for order %s: %v", number, err)
+	}
+}
+
+func isValidLuhn(number string) bool {
+	if number == "" {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func main() {
+	cfg := newConfig()
+	s := newServer(cfg)
+
+	log.Printf("accrual mock listening on %s", cfg.RunAddress)
+	if err := http.ListenAndServe(cfg.RunAddress, s.routes()); err != nil {
+		log.Fatal(err)
+	}
+}
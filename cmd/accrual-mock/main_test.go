@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestServer(cfg config) (*server, *testClock) {
+	clock := &testClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := newServer(cfg)
+	s.now = clock.now
+	return s, clock
+}
+
+func get(t *testing.T, h http.Handler, number string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/orders/"+number, nil))
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return body
+}
+
+func TestStatusProgression(t *testing.T) {
+	s, clock := newTestServer(config{Step: time.Second, Accrual: 500, AutoRegister: true})
+	h := s.routes()
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		status  string
+	}{
+		{name: "registered", advance: 0, status: "REGISTERED"},
+		{name: "processing", advance: time.Second, status: "PROCESSING"},
+		{name: "processed", advance: time.Second, status: "PROCESSED"},
+	}
+	for _, tt := range tests {
+		clock.advance(tt.advance)
+		rec := get(t, h, "79927398713")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status code = %d, want %d", tt.name, rec.Code, http.StatusOK)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("%s: Content-Type = %q, want application/json", tt.name, got)
+		}
+		body := decode(t, rec)
+		if body["order"] != "79927398713" {
+			t.Errorf("%s: order = %v, want 79927398713", tt.name, body["order"])
+		}
+		if body["status"] != tt.status {
+			t.Errorf("%s: status = %v, want %s", tt.name, body["status"], tt.status)
+		}
+		accrual, ok := body["accrual"]
+		if tt.status == "PROCESSED" {
+			if accrual != 500.0 {
+				t.Errorf("%s: accrual = %v, want 500", tt.name, accrual)
+			}
+		} else if ok {
+			t.Errorf("%s: unexpected accrual %v", tt.name, accrual)
+		}
+	}
+}
+
+func TestInvalidOrder(t *testing.T) {
+	s, _ := newTestServer(config{Step: time.Second, AutoRegister: true})
+	h := s.routes()
+
+	for _, number := range []string{"79927398710", "7992739871a", "abc"} {
+		rec := get(t, h, number)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status code = %d, want %d", number, rec.Code, http.StatusOK)
+		}
+		body := decode(t, rec)
+		if body["status"] != "INVALID" {
+			t.Errorf("%s: status = %v, want INVALID", number, body["status"])
+		}
+		if _, ok := body["accrual"]; ok {
+			t.Errorf("%s: unexpected accrual %v", number, body["accrual"])
+		}
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	s, _ := newTestServer(config{Step: time.Second})
+	h := s.routes()
+
+	if rec := get(t, h, "79927398713"); rec.Code != http.StatusNoContent {
+		t.Fatalf("unregistered: status code = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(body)))
+		return rec.Code
+	}
+	if code := post(`{"order": "79927398713"}`); code != http.StatusAccepted {
+		t.Fatalf("register: status code = %d, want %d", code, http.StatusAccepted)
+	}
+	if code := post(`{"order": "79927398713"}`); code != http.StatusConflict {
+		t.Errorf("register twice: status code = %d, want %d", code, http.StatusConflict)
+	}
+	if code := post(`{}`); code != http.StatusBadRequest {
+		t.Errorf("register without order: status code = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	rec := get(t, h, "79927398713")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("registered: status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if body := decode(t, rec); body["status"] != "REGISTERED" {
+		t.Errorf("registered: status = %v, want REGISTERED", body["status"])
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s, clock := newTestServer(config{Step: time.Second, RateLimit: 2, AutoRegister: true})
+	h := s.routes()
+
+	for i := 0; i < 2; i++ {
+		if rec := get(t, h, "79927398713"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status code = %d, want %d", i+1, rec.Code, http.StatusOK)
+		}
+	}
+
+	rec := get(t, h, "79927398713")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over limit: status code = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %q, want text/plain", got)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+	if got, want := rec.Body.String(), "No more than 2 requests per minute allowed"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	clock.advance(time.Minute)
+	if rec := get(t, h, "79927398713"); rec.Code != http.StatusOK {
+		t.Errorf("next window: status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s, _ := newTestServer(config{Step: time.Second, AutoRegister: true})
+	h := s.routes()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders/79927398713", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status code = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}